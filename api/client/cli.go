@@ -0,0 +1,60 @@
+// Package client implements the docker command-line client.
+package client
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// DockerCli represents the docker command line client, holding the
+// connection to the daemon and the client's standard streams.
+type DockerCli struct {
+	proto string
+	addr  string
+
+	in  io.ReadCloser
+	out io.Writer
+	err io.Writer
+
+	transport http.RoundTripper
+}
+
+// NewDockerCli returns a DockerCli that talks to the daemon listening on
+// proto/addr (e.g. "unix", "/var/run/docker.sock").
+func NewDockerCli(in io.ReadCloser, out, err io.Writer, proto, addr string) *DockerCli {
+	return &DockerCli{
+		proto: proto,
+		addr:  addr,
+		in:    in,
+		out:   out,
+		err:   err,
+		transport: &http.Transport{
+			Dial: func(network, address string) (net.Conn, error) {
+				return net.Dial(proto, addr)
+			},
+		},
+	}
+}
+
+// call issues an HTTP request to the daemon at path and returns the
+// response body for the caller to read (and close).
+func (cli *DockerCli) call(method, path string, body io.Reader) (io.ReadCloser, error) {
+	req, err := http.NewRequest(method, "http://docker"+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := cli.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Error response from daemon: %s", resp.Status)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/dongooo/docker/pkg/archive"
+	flag "github.com/dongooo/docker/pkg/mflag"
+)
+
+// CmdCp copies files/folders between a container and the local filesystem,
+// in either direction:
+//
+//	docker cp CONTAINER:SRC_PATH DEST_PATH|-
+//	docker cp SRC_PATH|- CONTAINER:DEST_PATH
+//
+// Exactly one of SRC_PATH/DEST_PATH must be a CONTAINER:PATH.
+func (cli *DockerCli) CmdCp(args ...string) error {
+	cmd := flag.NewFlagSet("cp", flag.ExitOnError)
+	cmd.Usage = func() {
+		fmt.Fprintln(cli.err, "Usage: docker cp [OPTIONS] SRC_PATH|- CONTAINER:DEST_PATH|CONTAINER:SRC_PATH DEST_PATH|-")
+	}
+
+	archiveMode := cmd.Bool([]string{"a", "-archive"}, false, "Archive mode (preserve uid/gid, mode, and xattrs)")
+	chown := cmd.String([]string{"-chown"}, "", "Set owner (uid:gid or user:group) on the copied file(s)")
+
+	var excludes stringSliceFlag
+	cmd.Var(&excludes, []string{"-exclude"}, "Exclude files matching a pattern, repeatable (.dockerignore syntax)")
+
+	if err := cmd.Parse(args); err != nil {
+		return err
+	}
+
+	if cmd.NArg() != 2 {
+		cmd.Usage()
+		return fmt.Errorf("docker cp requires exactly 2 arguments")
+	}
+
+	src, dst := cmd.Arg(0), cmd.Arg(1)
+
+	srcContainer, srcPath, srcIsContainer := splitContainerPath(src)
+	dstContainer, dstPath, dstIsContainer := splitContainerPath(dst)
+
+	switch {
+	case srcIsContainer && !dstIsContainer:
+		return cli.copyFromContainer(srcContainer, srcPath, dstPath, *chown, *archiveMode, excludes)
+	case !srcIsContainer && dstIsContainer:
+		return cli.copyToContainer(srcPath, dstContainer, dstPath, *archiveMode, *chown)
+	default:
+		return fmt.Errorf("one of SRC_PATH or DEST_PATH must specify a CONTAINER:PATH, and not both")
+	}
+}
+
+// splitContainerPath splits "CONTAINER:PATH" into its parts. A bare "-"
+// (stdin/stdout) is never a CONTAINER:PATH.
+func splitContainerPath(arg string) (container, path string, ok bool) {
+	if arg == "-" {
+		return "", arg, false
+	}
+	i := strings.Index(arg, ":")
+	if i <= 0 {
+		return "", arg, false
+	}
+	return arg[:i], arg[i+1:], true
+}
+
+// copyToContainer implements the upload half of `docker cp`: it tars up
+// srcPath (or, for "-", streams stdin straight through, already a tar
+// stream) and PUTs it to the daemon's archive endpoint for extraction
+// inside the container.
+func (cli *DockerCli) copyToContainer(srcPath, container, dstPath string, archiveMode bool, chown string) error {
+	var content io.ReadCloser
+
+	if srcPath == "-" {
+		content = cli.in
+	} else {
+		r, err := archive.Tar(srcPath, &archive.TarOptions{})
+		if err != nil {
+			return err
+		}
+		content = r
+	}
+	defer content.Close()
+
+	query := url.Values{}
+	query.Set("path", dstPath)
+	if archiveMode {
+		query.Set("archive", "1")
+	}
+	if chown != "" {
+		query.Set("chown", chown)
+	}
+
+	resp, err := cli.call("PUT", "/containers/"+container+"/archive?"+query.Encode(), content)
+	if err != nil {
+		return err
+	}
+	resp.Close()
+	return nil
+}
+
+// copyFromContainer implements the download half of `docker cp`: it GETs a
+// tar stream of srcPath from the daemon's archive endpoint and extracts it
+// onto dstPath (or, for "-", streams the raw tar to stdout). chown, if set,
+// overrides the owner of the copied file(s) on the host, resolving names
+// against the host's own /etc/passwd and /etc/group.
+func (cli *DockerCli) copyFromContainer(container, srcPath, dstPath, chown string, archiveMode bool, excludes stringSliceFlag) error {
+	query := url.Values{}
+	query.Set("path", srcPath)
+	for _, pattern := range excludes {
+		query.Add("exclude", pattern)
+	}
+
+	content, err := cli.call("GET", "/containers/"+container+"/archive?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	if dstPath == "-" {
+		_, err := io.Copy(cli.out, content)
+		return err
+	}
+
+	if err := os.MkdirAll(dstPath, 0755); err != nil {
+		return err
+	}
+
+	opts := &archive.TarOptions{
+		NoLchown:   !archiveMode,
+		DefaultUID: os.Getuid(),
+		DefaultGID: os.Getgid(),
+	}
+
+	if chown != "" {
+		passwd, _ := ioutil.ReadFile("/etc/passwd")
+		group, _ := ioutil.ReadFile("/etc/group")
+		chownOpts, err := archive.ParseChown(chown, passwd, group)
+		if err != nil {
+			return err
+		}
+		opts.Chown = chownOpts
+	}
+
+	return archive.Untar(content, dstPath, opts)
+}
+
+// stringSliceFlag implements flag.Value to collect a repeatable flag, such
+// as --exclude, into a slice.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,70 @@
+// Package server exposes the daemon over HTTP.
+package server
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/dongooo/docker/daemon"
+)
+
+// archiveRouter wires the GET/PUT /containers/{name}/archive endpoints used
+// by `docker cp` onto a Daemon. The full api/server mux registers this
+// alongside the rest of the container routes; it's split out here because
+// it's the only pair of handlers cp needs.
+type archiveRouter struct {
+	daemon *daemon.Daemon
+}
+
+func newArchiveRouter(d *daemon.Daemon) *archiveRouter {
+	return &archiveRouter{daemon: d}
+}
+
+// ServeHTTP dispatches GET (download) and PUT (upload) requests for
+// /containers/{name}/archive. name is expected to have already been
+// extracted from the request URL by the caller (the real mux does this with
+// a path pattern); path is read here from the "path" query parameter.
+func (ar *archiveRouter) ServeHTTP(w http.ResponseWriter, r *http.Request, name string) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "path is required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		ar.getContainerArchive(w, r, name, path)
+	case "PUT":
+		ar.putContainerArchive(w, r, name, path)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (ar *archiveRouter) getContainerArchive(w http.ResponseWriter, r *http.Request, name, path string) {
+	excludes := r.URL.Query()["exclude"]
+
+	content, err := ar.daemon.ContainerArchivePath(name, path, excludes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	if _, err := io.Copy(w, content); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (ar *archiveRouter) putContainerArchive(w http.ResponseWriter, r *http.Request, name, path string) {
+	query := r.URL.Query()
+	archiveMode := query.Get("archive") == "1"
+	chown := query.Get("chown")
+
+	if err := ar.daemon.ContainerExtractToDir(name, path, archiveMode, chown, r.Body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,171 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/dongooo/docker/pkg/archive"
+)
+
+// releaseOnCloseReader wraps an io.ReadCloser so that a resource held for
+// its duration is released when the reader is closed rather than when it's
+// returned. archive.Tar streams from a background goroutine and returns its
+// pipe reader immediately, so releasing eagerly (e.g. in a defer alongside
+// the call that produces the reader) would free the resource while the tar
+// walk is still reading from it.
+type releaseOnCloseReader struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (r *releaseOnCloseReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
+
+// Daemon is the subset of the real daemon.Daemon needed to serve the cp
+// endpoints: looking containers up by ID and extracting a rootfs path
+// for them on demand.
+type Daemon struct {
+	mu         sync.Mutex
+	containers map[string]*Container
+	mountRefs  map[string]*mountRef
+
+	// graphDriver mounts a non-running container's rootfs on demand so cp
+	// can reach it; nil disables mounting created/exited containers.
+	graphDriver GraphDriver
+}
+
+// NewDaemon constructs an empty Daemon. The real daemon.Daemon populates
+// containers from the on-disk container store at startup; tests and the cp
+// endpoints below only need Get/Register.
+func NewDaemon(graphDriver GraphDriver) *Daemon {
+	return &Daemon{
+		containers:  make(map[string]*Container),
+		mountRefs:   make(map[string]*mountRef),
+		graphDriver: graphDriver,
+	}
+}
+
+// Register adds (or replaces) a container the daemon knows about.
+func (daemon *Daemon) Register(c *Container) {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+	daemon.containers[c.ID] = c
+}
+
+// Get looks up a container by the ID it was registered under; names are
+// not resolved.
+func (daemon *Daemon) Get(name string) (*Container, error) {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+	c, ok := daemon.containers[name]
+	if !ok {
+		return nil, fmt.Errorf("no such container: %s", name)
+	}
+	return c, nil
+}
+
+// ContainerExtractToDir serves the upload half of `docker cp`: it resolves
+// path against the container's rootfs (following symlinks the same way the
+// download path does, so a crafted symlink component can't escape the
+// rootfs) and extracts the tar stream in src there.
+//
+// Without archiveMode, extracted entries are owned by the container's
+// root uid/gid mapping rather than whatever the archive recorded for them.
+// With archiveMode, the archive's recorded owner wins instead. The mode
+// and xattrs recorded in the archive are restored in both modes. chown, if
+// non-empty ("uid:gid" or "user:group"), overrides the
+// owner either way, resolving names against the container's own
+// /etc/passwd and /etc/group.
+func (daemon *Daemon) ContainerExtractToDir(name, path string, archiveMode bool, chown string, src io.Reader) error {
+	container, err := daemon.Get(name)
+	if err != nil {
+		return err
+	}
+
+	rootfs, err := daemon.containerRootfs(container)
+	if err != nil {
+		return err
+	}
+	defer daemon.releaseContainerRootfs(container)
+
+	dstPath, err := archive.ResolveInRootfs(rootfs, path)
+	if err != nil {
+		return err
+	}
+
+	opts := &archive.TarOptions{
+		NoLchown:   !archiveMode,
+		DefaultUID: container.RootUID,
+		DefaultGID: container.RootGID,
+	}
+
+	if chown != "" {
+		passwd, _ := ioutil.ReadFile(filepath.Join(rootfs, "etc", "passwd"))
+		group, _ := ioutil.ReadFile(filepath.Join(rootfs, "etc", "group"))
+		chownOpts, err := archive.ParseChown(chown, passwd, group)
+		if err != nil {
+			return err
+		}
+		opts.Chown = chownOpts
+	}
+
+	return archive.Untar(src, dstPath, opts)
+}
+
+// ContainerArchivePath serves the download half of `docker cp`: it resolves
+// path against the container's rootfs and streams a tar archive of it back
+// to the caller. path may contain shell-style wildcards, evaluated against
+// the rootfs only after the same symlink-containment resolution used for a
+// literal path, so a crafted symlink can't expand a glob outside the
+// rootfs. excludes filters out matching entries in .dockerignore syntax.
+func (daemon *Daemon) ContainerArchivePath(name, path string, excludes []string) (io.ReadCloser, error) {
+	container, err := daemon.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rootfs, err := daemon.containerRootfs(container)
+	if err != nil {
+		return nil, err
+	}
+	release := func() { daemon.releaseContainerRootfs(container) }
+
+	content, err := daemon.tarContainerPath(rootfs, path, excludes)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return &releaseOnCloseReader{ReadCloser: content, release: release}, nil
+}
+
+// tarContainerPath resolves path (literal or glob) against rootfs and
+// starts streaming a tar archive of the result. Split out of
+// ContainerArchivePath so the rootfs ref can be released on the returned
+// reader's Close rather than the moment this returns.
+func (daemon *Daemon) tarContainerPath(rootfs, path string, excludes []string) (io.ReadCloser, error) {
+	if archive.IsGlob(path) {
+		matches, err := archive.ResolveGlobInRootfs(rootfs, path)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no such file or directory: %s", path)
+		}
+		return archive.Tar("", &archive.TarOptions{IncludeFiles: matches, ExcludePatterns: excludes})
+	}
+
+	srcPath, err := archive.ResolveInRootfs(rootfs, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return archive.Tar(srcPath, &archive.TarOptions{ExcludePatterns: excludes})
+}
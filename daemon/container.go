@@ -0,0 +1,31 @@
+package daemon
+
+// Container is the subset of per-container state the cp endpoints need:
+// where its rootfs lives on the host and what uid/gid new files should be
+// written as when no explicit owner is given. The rest of a container's
+// configuration, networking, and logging state lives alongside this in the
+// full daemon.Container type.
+type Container struct {
+	ID    string
+	State *State
+
+	// BaseFS is the path to the container's rootfs on the host, valid
+	// whenever the rootfs is mounted (State.Running or the mount has been
+	// taken on demand for cp against a non-running container).
+	BaseFS string
+
+	// RootUID/RootGID are the uid/gid new, un-owned files should be
+	// written as, per the container's user namespace mapping.
+	RootUID int
+	RootGID int
+}
+
+// State tracks the lifecycle of a container as relevant to cp: whether its
+// rootfs is mounted and, if paused, whether cp should avoid unpausing it.
+type State struct {
+	Running bool
+	Paused  bool
+	// Created is true for a container that has never been started; its
+	// rootfs still needs to be mounted on demand for cp to reach it.
+	Created bool
+}
@@ -0,0 +1,86 @@
+package daemon
+
+import (
+	"fmt"
+	"sync"
+)
+
+// GraphDriver is the subset of the storage driver interface cp needs to
+// reach the rootfs of a container that isn't currently running: mounting
+// its layer on demand and releasing it again afterwards.
+type GraphDriver interface {
+	Get(id string) (string, error)
+	Put(id string) error
+}
+
+// mountRef serializes on-demand mount/unmount around a single container so
+// a cp in flight holds the rootfs in place for its duration.
+type mountRef struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (daemon *Daemon) refFor(id string) *mountRef {
+	daemon.mu.Lock()
+	defer daemon.mu.Unlock()
+	ref, ok := daemon.mountRefs[id]
+	if !ok {
+		ref = &mountRef{}
+		daemon.mountRefs[id] = ref
+	}
+	return ref
+}
+
+// containerRootfs returns the host path of container's rootfs. For a
+// running (including paused) container the rootfs is already mounted, so
+// this just returns it; cp never touches State.Paused, so it can't have the
+// side effect of unpausing the container. For a created or exited
+// container it mounts the rootfs on demand through the graph driver and
+// holds it locked until releaseContainerRootfs - "freezing" the mount so a
+// concurrent start/rm can't pull it out from under an in-flight cp.
+func (daemon *Daemon) containerRootfs(container *Container) (string, error) {
+	if container.State.Running {
+		if container.BaseFS == "" {
+			return "", fmt.Errorf("container %s has no rootfs mounted", container.ID)
+		}
+		return container.BaseFS, nil
+	}
+
+	if daemon.graphDriver == nil {
+		return "", fmt.Errorf("container %s is not running and no graph driver is configured to mount it", container.ID)
+	}
+
+	ref := daemon.refFor(container.ID)
+	ref.mu.Lock()
+
+	rootfs, err := daemon.graphDriver.Get(container.ID)
+	if err != nil {
+		ref.mu.Unlock()
+		return "", err
+	}
+
+	ref.count++
+	container.BaseFS = rootfs
+	// ref.mu stays locked until releaseContainerRootfs unlocks it.
+	return rootfs, nil
+}
+
+// releaseContainerRootfs is the counterpart to containerRootfs: for a
+// running container there is nothing to release; for a container mounted
+// on demand it unmounts the rootfs and unlocks the mount, so the next cp
+// waiting on it (cps against one such container run one at a time) can
+// proceed.
+func (daemon *Daemon) releaseContainerRootfs(container *Container) {
+	if container.State.Running {
+		return
+	}
+
+	ref := daemon.refFor(container.ID)
+	defer ref.mu.Unlock()
+
+	ref.count--
+	if ref.count <= 0 && daemon.graphDriver != nil {
+		daemon.graphDriver.Put(container.ID)
+		container.BaseFS = ""
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 )
 
@@ -20,6 +21,7 @@ const (
 
 	cpContainerContents = "holla, i am the container"
 	cpHostContents      = "hello, i am the host"
+	cpUploadContents    = "sup, i am the upload"
 )
 
 // Test for #5656
@@ -331,6 +333,715 @@ func TestCpSymlinkComponent(t *testing.T) {
 	logDone("cp - symlink path components relative to container's rootfs")
 }
 
+// Check that uploading to a garbage path doesn't escape the container's rootfs
+func TestCpToContainerGarbagePath(t *testing.T) {
+	out, exitCode := dockerCmd(t, "run", "-d", "busybox", "/bin/sh", "-c", "mkdir -p '"+cpTestPath+"' && echo -n '"+cpContainerContents+"' > "+cpFullPath+" && sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	if err := os.MkdirAll(cpTestPath, os.ModeDir); err != nil {
+		t.Fatal(err)
+	}
+
+	hostFile, err := os.Create(cpFullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hostFile.Close()
+	defer os.RemoveAll(cpTestPathParent)
+
+	fmt.Fprintf(hostFile, "%s", cpHostContents)
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcName := filepath.Join(tmpdir, cpTestName)
+	if err := ioutil.WriteFile(srcName, []byte(cpUploadContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path := path.Join("../../../../../../../../../../../../", cpFullPath)
+
+	_, _ = dockerCmd(t, "cp", srcName, cleanedContainerID+":"+path)
+
+	hostContent, err := ioutil.ReadFile(cpFullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(hostContent) == cpUploadContents {
+		t.Errorf("uploaded content overwrote the host file -- garbage path can escape container rootfs")
+	}
+
+	containerContent, _ := dockerCmd(t, "exec", cleanedContainerID, "cat", cpFullPath)
+	if strings.TrimSpace(containerContent) != cpUploadContents {
+		t.Errorf("uploaded content not found inside the container for garbage path")
+	}
+
+	logDone("cp - upload to garbage path relative to container's rootfs")
+}
+
+// Check that uploading to a relative path lands relative to the container's rootfs
+func TestCpToContainerRelativePath(t *testing.T) {
+	out, exitCode := dockerCmd(t, "run", "-d", "busybox", "/bin/sh", "-c", "mkdir -p '"+cpTestPath+"' && echo -n '"+cpContainerContents+"' > "+cpFullPath+" && sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	if err := os.MkdirAll(cpTestPath, os.ModeDir); err != nil {
+		t.Fatal(err)
+	}
+
+	hostFile, err := os.Create(cpFullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hostFile.Close()
+	defer os.RemoveAll(cpTestPathParent)
+
+	fmt.Fprintf(hostFile, "%s", cpHostContents)
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcName := filepath.Join(tmpdir, cpTestName)
+	if err := ioutil.WriteFile(srcName, []byte(cpUploadContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var relPath string
+	if path.IsAbs(cpFullPath) {
+		relPath = cpFullPath[1:]
+	} else {
+		t.Fatalf("path %s was assumed to be an absolute path", cpFullPath)
+	}
+
+	_, _ = dockerCmd(t, "cp", srcName, cleanedContainerID+":"+relPath)
+
+	hostContent, err := ioutil.ReadFile(cpFullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(hostContent) == cpUploadContents {
+		t.Errorf("uploaded content overwrote the host file -- relative path can escape container rootfs")
+	}
+
+	containerContent, _ := dockerCmd(t, "exec", cleanedContainerID, "cat", cpFullPath)
+	if strings.TrimSpace(containerContent) != cpUploadContents {
+		t.Errorf("uploaded content not found inside the container for relative path")
+	}
+
+	logDone("cp - upload to relative path relative to container's rootfs")
+}
+
+// Check that uploading to an absolute path lands relative to the container's rootfs
+func TestCpToContainerAbsolutePath(t *testing.T) {
+	out, exitCode := dockerCmd(t, "run", "-d", "busybox", "/bin/sh", "-c", "mkdir -p '"+cpTestPath+"' && echo -n '"+cpContainerContents+"' > "+cpFullPath+" && sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	if err := os.MkdirAll(cpTestPath, os.ModeDir); err != nil {
+		t.Fatal(err)
+	}
+
+	hostFile, err := os.Create(cpFullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hostFile.Close()
+	defer os.RemoveAll(cpTestPathParent)
+
+	fmt.Fprintf(hostFile, "%s", cpHostContents)
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcName := filepath.Join(tmpdir, cpTestName)
+	if err := ioutil.WriteFile(srcName, []byte(cpUploadContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = dockerCmd(t, "cp", srcName, cleanedContainerID+":"+cpFullPath)
+
+	hostContent, err := ioutil.ReadFile(cpFullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(hostContent) == cpUploadContents {
+		t.Errorf("uploaded content overwrote the host file -- absolute path can escape container rootfs")
+	}
+
+	containerContent, _ := dockerCmd(t, "exec", cleanedContainerID, "cat", cpFullPath)
+	if strings.TrimSpace(containerContent) != cpUploadContents {
+		t.Errorf("uploaded content not found inside the container for absolute path")
+	}
+
+	logDone("cp - upload to absolute path relative to container's rootfs")
+}
+
+// Check that uploading through a symlink path component lands relative to the container's rootfs
+func TestCpToContainerSymlinkComponent(t *testing.T) {
+	out, exitCode := dockerCmd(t, "run", "-d", "busybox", "/bin/sh", "-c", "mkdir -p '"+cpTestPath+"' && echo -n '"+cpContainerContents+"' > "+cpFullPath+" && ln -s "+cpTestPath+" container_path && sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	if err := os.MkdirAll(cpTestPath, os.ModeDir); err != nil {
+		t.Fatal(err)
+	}
+
+	hostFile, err := os.Create(cpFullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hostFile.Close()
+	defer os.RemoveAll(cpTestPathParent)
+
+	fmt.Fprintf(hostFile, "%s", cpHostContents)
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcName := filepath.Join(tmpdir, cpTestName)
+	if err := ioutil.WriteFile(srcName, []byte(cpUploadContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dstPath := path.Join("/", "container_path", cpTestName)
+
+	_, _ = dockerCmd(t, "cp", srcName, cleanedContainerID+":"+dstPath)
+
+	hostContent, err := ioutil.ReadFile(cpFullPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(hostContent) == cpUploadContents {
+		t.Errorf("uploaded content overwrote the host file -- symlink path component can escape container rootfs")
+	}
+
+	containerContent, _ := dockerCmd(t, "exec", cleanedContainerID, "cat", cpFullPath)
+	if strings.TrimSpace(containerContent) != cpUploadContents {
+		t.Errorf("uploaded content not found inside the container for symlink path component")
+	}
+
+	logDone("cp - upload through symlink path component relative to container's rootfs")
+}
+
+// Check that uploading through a symlink pointing at /etc/passwd cannot escape the
+// container's rootfs and overwrite the host's /etc/passwd
+func TestCpToContainerSymlinkEscapesToPasswd(t *testing.T) {
+	out, exitCode := dockerCmd(t, "run", "-d", "busybox", "/bin/sh", "-c", "ln -s /etc/passwd container_passwd && sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	hostPasswd, err := ioutil.ReadFile("/etc/passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcName := filepath.Join(tmpdir, cpTestName)
+	if err := ioutil.WriteFile(srcName, []byte(cpUploadContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = dockerCmd(t, "cp", srcName, cleanedContainerID+":/container_passwd")
+
+	afterHostPasswd, err := ioutil.ReadFile("/etc/passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(hostPasswd, afterHostPasswd) {
+		t.Fatal("uploading through a symlink overwrote the host's /etc/passwd")
+	}
+
+	containerContent, _ := dockerCmd(t, "exec", cleanedContainerID, "cat", "/etc/passwd")
+	if strings.TrimSpace(containerContent) != cpUploadContents {
+		t.Errorf("uploaded content not found at the container's /etc/passwd")
+	}
+
+	logDone("cp - upload through symlink to /etc/passwd cannot escape container rootfs")
+}
+
+// Check that cp can stream a tar archive from stdin into a container
+func TestCpFromStdin(t *testing.T) {
+	out, exitCode := dockerCmd(t, "run", "-d", "busybox", "/bin/sh", "-c", "mkdir -p '"+cpTestPathParent+"' && sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcName := filepath.Join(tmpdir, cpTestName)
+	if err := ioutil.WriteFile(srcName, []byte(cpUploadContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = runCommandPipelineWithOutput(
+		exec.Command("tar", "-cf", "-", "-C", tmpdir, cpTestName),
+		exec.Command(dockerBinary, "cp", "-", cleanedContainerID+":"+cpTestPathParent))
+	if err != nil {
+		t.Fatalf("Failed to run commands: %s", err)
+	}
+
+	containerContent, _ := dockerCmd(t, "exec", cleanedContainerID, "cat", cpTestPathParent+"/"+cpTestName)
+	if strings.TrimSpace(containerContent) != cpUploadContents {
+		t.Errorf("content streamed from stdin not found inside the container")
+	}
+
+	logDone("cp - upload streaming a tar archive from stdin")
+}
+
+// Check that cp can read a file out of a container that has been created but never started
+func TestCpFromCreatedContainer(t *testing.T) {
+	out, exitCode := dockerCmd(t, "create", "busybox", "/bin/true")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	_, _ = dockerCmd(t, "cp", cleanedContainerID+":/etc/passwd", tmpdir)
+
+	content, err := ioutil.ReadFile(filepath.Join(tmpdir, "passwd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(content) == 0 {
+		t.Fatal("expected non-empty /etc/passwd copied out of a created container")
+	}
+
+	logDone("cp - from a created container")
+}
+
+// Check that cp can read a file out of a paused container, and that doing so
+// doesn't unpause the container as a side-effect
+func TestCpFromPausedContainer(t *testing.T) {
+	testRequires(t, SameHostDaemon)
+
+	out, exitCode := dockerCmd(t, "run", "-d", "busybox", "/bin/sh", "-c", "echo -n '"+cpContainerContents+"' > /test && sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	dockerCmd(t, "pause", cleanedContainerID)
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	_, _ = dockerCmd(t, "cp", cleanedContainerID+":/test", tmpdir)
+
+	content, err := ioutil.ReadFile(filepath.Join(tmpdir, "test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != cpContainerContents {
+		t.Fatalf("output doesn't match the input for paused container: %q", content)
+	}
+
+	state, _ := dockerCmd(t, "inspect", "-f", "{{.State.Paused}}", cleanedContainerID)
+	if strings.TrimSpace(state) != "true" {
+		t.Fatal("cp unpaused the container as a side-effect")
+	}
+
+	dockerCmd(t, "unpause", cleanedContainerID)
+
+	logDone("cp - from a paused container without unpausing it")
+}
+
+// Check that cp can seed a file into a created container before it is ever started
+func TestCpToCreatedContainer(t *testing.T) {
+	out, exitCode := dockerCmd(t, "create", "busybox", "/bin/sh", "-c", "cat /seeded > /seeded-out")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcName := filepath.Join(tmpdir, "seeded")
+	if err := ioutil.WriteFile(srcName, []byte(cpUploadContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = dockerCmd(t, "cp", srcName, cleanedContainerID+":/seeded")
+
+	dockerCmd(t, "start", cleanedContainerID)
+
+	out, _ = dockerCmd(t, "wait", cleanedContainerID)
+	if strings.TrimSpace(out) != "0" {
+		t.Fatal("failed to run seeded container", out)
+	}
+
+	outDir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	_, _ = dockerCmd(t, "cp", cleanedContainerID+":/seeded-out", outDir)
+
+	content, err := ioutil.ReadFile(filepath.Join(outDir, "seeded-out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != cpUploadContents {
+		t.Fatalf("seeded file not present after start: %q", content)
+	}
+
+	logDone("cp - to a created container, seeded file present after start")
+}
+
+// Check that archive-mode cp round-trips extended attributes such as file capabilities
+func TestCpArchiveModePreservesCapabilities(t *testing.T) {
+	testRequires(t, SameHostDaemon)
+
+	if _, err := exec.LookPath("setcap"); err != nil {
+		t.Skip("setcap not installed")
+	}
+	if _, err := exec.LookPath("getcap"); err != nil {
+		t.Skip("getcap not installed")
+	}
+
+	out, exitCode := dockerCmd(t, "run", "-d", "busybox", "/bin/sh", "-c", "sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcName := filepath.Join(tmpdir, "capbin")
+	if err := ioutil.WriteFile(srcName, []byte(cpUploadContents), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if capOut, _, err := runCommandWithOutput(exec.Command("setcap", "cap_net_bind_service+ep", srcName)); err != nil {
+		t.Fatalf("failed to set capability on host file: %s %s", capOut, err)
+	}
+
+	_, _ = dockerCmd(t, "cp", "-a", srcName, cleanedContainerID+":/capbin")
+
+	outDir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	_, _ = dockerCmd(t, "cp", "-a", cleanedContainerID+":/capbin", outDir)
+
+	capOut, _, err := runCommandWithOutput(exec.Command("getcap", filepath.Join(outDir, "capbin")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(capOut, "cap_net_bind_service") {
+		t.Fatalf("expected cap_net_bind_service to survive an archive-mode cp round-trip, got: %q", capOut)
+	}
+
+	logDone("cp - archive mode preserves capabilities")
+}
+
+// Check that archive-mode cp round-trips permissions and ownership
+func TestCpArchiveModePreservesOwnershipAndMode(t *testing.T) {
+	testRequires(t, SameHostDaemon)
+	testRequires(t, UnixCli)
+
+	out, exitCode := dockerCmd(t, "run", "-d", "busybox", "/bin/sh", "-c", "sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcName := filepath.Join(tmpdir, "owned")
+	if err := ioutil.WriteFile(srcName, []byte(cpUploadContents), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(srcName, 1000, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = dockerCmd(t, "cp", "-a", srcName, cleanedContainerID+":/owned")
+
+	outDir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	_, _ = dockerCmd(t, "cp", "-a", cleanedContainerID+":/owned", outDir)
+
+	stat, err := os.Stat(filepath.Join(outDir, "owned"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stat.Mode().Perm() != 0700 {
+		t.Fatalf("expected mode 0700 to survive an archive-mode cp round-trip, got %o", stat.Mode().Perm())
+	}
+
+	sysStat, ok := stat.Sys().(*syscall.Stat_t)
+	if !ok {
+		t.Fatal("unable to stat uid/gid of copied file")
+	}
+
+	if sysStat.Uid != 1000 || sysStat.Gid != 1000 {
+		t.Fatalf("expected uid/gid 1000:1000 to survive an archive-mode cp round-trip, got %d:%d", sysStat.Uid, sysStat.Gid)
+	}
+
+	logDone("cp - archive mode preserves ownership and mode")
+}
+
+// Check that --chown overrides the owner of a file copied into a container
+func TestCpChownOverridesOwner(t *testing.T) {
+	testRequires(t, SameHostDaemon)
+	testRequires(t, UnixCli)
+
+	out, exitCode := dockerCmd(t, "run", "-d", "busybox", "/bin/sh", "-c", "sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	tmpdir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	srcName := filepath.Join(tmpdir, "chowned")
+	if err := ioutil.WriteFile(srcName, []byte(cpUploadContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(srcName, 1000, 1000); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = dockerCmd(t, "cp", "-a", "--chown=root:root", srcName, cleanedContainerID+":/chowned")
+
+	ownerOut, _ := dockerCmd(t, "exec", cleanedContainerID, "stat", "-c", "%U:%G", "/chowned")
+	if strings.TrimSpace(ownerOut) != "root:root" {
+		t.Fatalf("expected --chown=root:root to override the uploaded file's owner, got %q", ownerOut)
+	}
+
+	logDone("cp - --chown overrides owner on upload")
+}
+
+// Check that a glob source path is evaluated inside the container's rootfs and
+// can't be crafted to pick up a host path of the same name
+func TestCpSourceGlobDoesNotMatchHostPaths(t *testing.T) {
+	testRequires(t, SameHostDaemon)
+
+	out, exitCode := dockerCmd(t, "run", "-d", "busybox", "/bin/sh", "-c", "sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer deleteContainer(cleanedContainerID)
+
+	containerPasswd, _ := dockerCmd(t, "exec", cleanedContainerID, "cat", "/etc/passwd")
+
+	hostPasswd, err := ioutil.ReadFile("/etc/passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outDir, err := ioutil.TempDir("", "docker-integration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	_, _ = dockerCmd(t, "cp", cleanedContainerID+":/etc/pass*", outDir)
+
+	content, err := ioutil.ReadFile(filepath.Join(outDir, "passwd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) == string(hostPasswd) {
+		t.Fatal("glob source matched the host's /etc/passwd instead of the container's")
+	}
+
+	if strings.TrimSpace(string(content)) != strings.TrimSpace(containerPasswd) {
+		t.Fatalf("glob source didn't match the container's /etc/passwd, got: %q", content)
+	}
+
+	logDone("cp - source glob relative to container's rootfs")
+}
+
+// Check that --exclude filters out a matching file nested in a bind-mounted volume
+func TestCpExcludeVolumePath(t *testing.T) {
+	testRequires(t, SameHostDaemon)
+
+	tmpDir, err := ioutil.TempDir("", "cp-test-exclude-volumepath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, "keep"), []byte(cpHostContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, "skip.log"), []byte(cpHostContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, exitCode := dockerCmd(t, "run", "-d", "-v", tmpDir+":/baz", "busybox", "/bin/sh", "-c", "sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer dockerCmd(t, "rm", "-fv", cleanedContainerID)
+
+	outDir, err := ioutil.TempDir("", "cp-test-exclude-volumepath-out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	_, _ = dockerCmd(t, "cp", "--exclude=skip.log", cleanedContainerID+":/baz", outDir)
+
+	if _, err := os.Stat(filepath.Join(outDir, "baz", "keep")); err != nil {
+		t.Fatalf("expected non-excluded file to be copied: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(outDir, "baz", "skip.log")); err == nil {
+		t.Fatal("expected --exclude=skip.log to filter out the nested file")
+	}
+
+	logDone("cp - --exclude filters a file nested in a bind-mounted volume")
+}
+
+// Check that a glob whose expansion crosses into a bind-mounted directory
+// produces the bind target's contents rather than the container rootfs's
+func TestCpSourceGlobCrossesBindMount(t *testing.T) {
+	testRequires(t, SameHostDaemon)
+
+	tmpDir, err := ioutil.TempDir("", "cp-test-glob-bindmount")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, "a.conf"), []byte(cpHostContents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, exitCode := dockerCmd(t, "run", "-d", "-v", tmpDir+":/data", "busybox", "/bin/sh", "-c", "sleep 10000")
+	if exitCode != 0 {
+		t.Fatal("failed to create a container", out)
+	}
+
+	cleanedContainerID := strings.TrimSpace(out)
+	defer dockerCmd(t, "rm", "-fv", cleanedContainerID)
+
+	outDir, err := ioutil.TempDir("", "cp-test-glob-bindmount-out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outDir)
+
+	_, _ = dockerCmd(t, "cp", cleanedContainerID+":/data/*.conf", outDir)
+
+	content, err := ioutil.ReadFile(filepath.Join(outDir, "a.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(content) != cpHostContents {
+		t.Fatalf("expected glob crossing a bind mount to produce the bind target's contents, got: %q", content)
+	}
+
+	logDone("cp - source glob crossing a bind-mounted directory")
+}
+
 // Check that cp with unprivileged user doesn't return any error
 func TestCpUnprivilegedUser(t *testing.T) {
 	testRequires(t, UnixCli) // uses chmod/su: not available on windows
@@ -0,0 +1,381 @@
+// Package archive provides helpers for streaming tar archives in and out of
+// a container's rootfs for `docker cp`, preserving (or rewriting) ownership,
+// mode, and extended attributes along the way.
+package archive
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrNotDirectory is returned when a destination that must be a directory
+// (because the source archive contains more than one entry) is not one.
+var ErrNotDirectory = errors.New("archive: destination exists and is not a directory")
+
+// ChownOpts overrides the uid/gid recorded for every entry extracted from an
+// archive, as set by `docker cp --chown`.
+type ChownOpts struct {
+	UID int
+	GID int
+}
+
+// TarOptions controls how Tar and Untar walk and (re)write archive entries.
+type TarOptions struct {
+	// IncludeFiles, when non-empty, makes Tar archive just these paths,
+	// used as given, in place of srcPath; each becomes a top-level entry
+	// named by its base name. A nil or empty slice archives srcPath.
+	IncludeFiles []string
+
+	// ExcludePatterns filters entries out of Tar in .dockerignore syntax:
+	// each pattern is a filepath.Match glob matched against an entry's
+	// path relative to the directory being archived, and against the base
+	// name of that path and of each of its parent directories, and a
+	// "!"-prefixed pattern re-includes
+	// a path an earlier pattern excluded.
+	ExcludePatterns []string
+
+	// NoLchown disables restoring the uid/gid recorded in the archive
+	// (plain, non-archive-mode cp); extracted entries are instead owned
+	// by DefaultUID/DefaultGID. Archive mode leaves this false so the
+	// archive's recorded owner wins.
+	NoLchown bool
+
+	// DefaultUID/DefaultGID is the owner applied to extracted entries
+	// when NoLchown is set and Chown is nil - the container's own
+	// uid/gid mapping on upload, or the caller's on download.
+	DefaultUID int
+	DefaultGID int
+
+	// Chown, when non-nil, overrides the uid/gid of every extracted
+	// entry, taking precedence over both the archive's recorded owner
+	// and NoLchown/DefaultUID/DefaultGID.
+	Chown *ChownOpts
+}
+
+// Tar walks srcPath and streams a tar archive of its contents. When srcPath
+// is a regular file, the archive contains that single entry. Ownership and
+// mode are recorded in each entry's header, and (on supported platforms)
+// extended attributes as PAX records, so that an archive-mode round-trip
+// can restore them exactly.
+func Tar(srcPath string, options *TarOptions) (io.ReadCloser, error) {
+	if options == nil {
+		options = &TarOptions{}
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+
+	go func() {
+		tw := tar.NewWriter(pipeWriter)
+
+		walkErr := func() error {
+			if len(options.IncludeFiles) == 0 {
+				return addTarEntry(tw, srcPath, filepath.Base(srcPath), options.ExcludePatterns)
+			}
+			for _, include := range options.IncludeFiles {
+				if err := addTarEntry(tw, include, filepath.Base(include), options.ExcludePatterns); err != nil {
+					return err
+				}
+			}
+			return nil
+		}()
+
+		if walkErr != nil {
+			pipeWriter.CloseWithError(walkErr)
+			return
+		}
+		if err := tw.Close(); err != nil {
+			pipeWriter.CloseWithError(err)
+			return
+		}
+		pipeWriter.Close()
+	}()
+
+	return pipeReader, nil
+}
+
+func addTarEntry(tw *tar.Writer, srcPath, nameInArchive string, excludes []string) error {
+	return filepath.Walk(srcPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(srcPath, path)
+		if err != nil {
+			return err
+		}
+
+		if relPath != "." && matchesExcludePatterns(relPath, excludes) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		hdrName := nameInArchive
+		if relPath != "." {
+			hdrName = filepath.Join(nameInArchive, relPath)
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = hdrName
+
+		if uid, gid, ok := lchown(path); ok {
+			hdr.Uid, hdr.Gid = uid, gid
+		}
+
+		if xattrs, err := lgetXattrs(path); err == nil && len(xattrs) > 0 {
+			if hdr.PAXRecords == nil {
+				hdr.PAXRecords = make(map[string]string)
+			}
+			for name, value := range xattrs {
+				hdr.PAXRecords[paxXattrKey+name] = string(value)
+			}
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			if _, err := io.Copy(tw, f); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Untar extracts the tar stream in src onto dstDir. If dstDir already
+// exists as a directory, archive entries are extracted into it under their
+// recorded names, same as `cp` copying into an existing directory. If it
+// doesn't, the archive's top-level entry is renamed to dstDir itself (and
+// anything nested under it follows), so that extracting a single-file
+// archive names the file dstDir directly rather than creating dstDir as a
+// directory containing it - the same behavior as `cp` naming a destination
+// that doesn't exist yet. Ownership recorded in the archive is restored
+// unless overridden by options; extended attributes recorded in the archive
+// are always restored.
+func Untar(src io.Reader, dstDir string, options *TarOptions) error {
+	if options == nil {
+		options = &TarOptions{}
+	}
+
+	dstIsDir := false
+	if info, err := os.Stat(dstDir); err == nil {
+		dstIsDir = info.IsDir()
+	}
+
+	tr := tar.NewReader(src)
+	first := true
+	var topName string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := hdr.Name
+		if !dstIsDir {
+			if first {
+				topName = name
+				name = ""
+			} else {
+				name = strings.TrimPrefix(name, topName+string(os.PathSeparator))
+			}
+		}
+		first = false
+
+		dstPath := dstDir
+		if name != "" {
+			dstPath = filepath.Join(dstDir, name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dstPath, os.FileMode(hdr.Mode)); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(hdr.Linkname, dstPath); err != nil {
+				return err
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(dstPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			f.Close()
+		}
+
+		if err := applyOwnership(dstPath, hdr, options); err != nil {
+			return err
+		}
+
+		if err := applyXattrs(dstPath, hdr); err != nil {
+			return err
+		}
+	}
+}
+
+func applyOwnership(dstPath string, hdr *tar.Header, options *TarOptions) error {
+	if options.Chown != nil {
+		return lchownPath(dstPath, options.Chown.UID, options.Chown.GID)
+	}
+	if options.NoLchown {
+		return lchownPath(dstPath, options.DefaultUID, options.DefaultGID)
+	}
+	return lchownPath(dstPath, hdr.Uid, hdr.Gid)
+}
+
+const paxXattrKey = "SCHILY.xattr."
+
+func applyXattrs(dstPath string, hdr *tar.Header) error {
+	for key, value := range hdr.PAXRecords {
+		if len(key) <= len(paxXattrKey) || key[:len(paxXattrKey)] != paxXattrKey {
+			continue
+		}
+		name := key[len(paxXattrKey):]
+		if err := lsetXattr(dstPath, name, []byte(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchesExcludePatterns reports whether relPath should be left out of the
+// archive per patterns, applied in order so a later "!"-prefixed pattern
+// can re-include a path an earlier one excluded.
+func matchesExcludePatterns(relPath string, patterns []string) bool {
+	excluded := false
+	for _, pattern := range patterns {
+		negate := strings.HasPrefix(pattern, "!")
+		if negate {
+			pattern = pattern[1:]
+		}
+		if matchesPattern(relPath, pattern) {
+			excluded = !negate
+		}
+	}
+	return excluded
+}
+
+// matchesPattern reports whether pattern (a filepath.Match glob) matches
+// relPath, its basename, or any of its parent directories - the same rule
+// .dockerignore uses so that e.g. "skip.log" excludes "sub/skip.log".
+func matchesPattern(relPath, pattern string) bool {
+	if ok, _ := filepath.Match(pattern, relPath); ok {
+		return true
+	}
+	for dir := relPath; dir != "." && dir != string(os.PathSeparator); dir = filepath.Dir(dir) {
+		if ok, _ := filepath.Match(pattern, filepath.Base(dir)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseChown parses a `--chown=uid:gid` or `--chown=user:group` value.
+// rootfsPasswd and rootfsGroup hold the contents of the receiving side's
+// /etc/passwd and /etc/group (the container's on upload, the host's on
+// download), used to resolve user and group names.
+func ParseChown(value string, rootfsPasswd, rootfsGroup []byte) (*ChownOpts, error) {
+	userPart, groupPart := value, ""
+	for i, r := range value {
+		if r == ':' {
+			userPart, groupPart = value[:i], value[i+1:]
+			break
+		}
+	}
+
+	uid, err := resolveID(userPart, rootfsPasswd)
+	if err != nil {
+		return nil, err
+	}
+
+	gid := uid
+	if groupPart != "" {
+		gid, err = resolveID(groupPart, rootfsGroup)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &ChownOpts{UID: uid, GID: gid}, nil
+}
+
+func resolveID(s string, passwdLike []byte) (int, error) {
+	if id, err := strconv.Atoi(s); err == nil {
+		return id, nil
+	}
+	return lookupNameInPasswd(s, passwdLike)
+}
+
+func lookupNameInPasswd(name string, passwdLike []byte) (int, error) {
+	lines := splitLines(passwdLike)
+	for _, line := range lines {
+		fields := splitColon(line)
+		if len(fields) >= 3 && fields[0] == name {
+			return strconv.Atoi(fields[2])
+		}
+	}
+	return 0, errors.New("archive: unknown user or group " + name)
+}
+
+func splitLines(b []byte) []string {
+	var lines []string
+	start := 0
+	for i, c := range b {
+		if c == '\n' {
+			lines = append(lines, string(b[start:i]))
+			start = i + 1
+		}
+	}
+	if start < len(b) {
+		lines = append(lines, string(b[start:]))
+	}
+	return lines
+}
+
+func splitColon(s string) []string {
+	var fields []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == ':' {
+			fields = append(fields, s[start:i])
+			start = i + 1
+		}
+	}
+	fields = append(fields, s[start:])
+	return fields
+}
@@ -0,0 +1,24 @@
+// +build linux darwin freebsd
+
+package archive
+
+import (
+	"os"
+	"syscall"
+)
+
+func lchown(path string) (uid, gid int, ok bool) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, 0, false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(stat.Uid), int(stat.Gid), true
+}
+
+func lchownPath(path string, uid, gid int) error {
+	return os.Lchown(path, uid, gid)
+}
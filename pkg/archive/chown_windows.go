@@ -0,0 +1,12 @@
+// +build windows
+
+package archive
+
+func lchown(path string) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+func lchownPath(path string, uid, gid int) error {
+	// Windows has no uid/gid concept; ownership is a no-op.
+	return nil
+}
@@ -0,0 +1,189 @@
+package archive
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSymlinkDepth bounds symlink-following while resolving a path inside a
+// rootfs, guarding against symlink loops.
+const maxSymlinkDepth = 40
+
+// ResolveInRootfs cleans path (which may be relative, contain "..", or walk
+// through symlinks) and resolves it against rootfs such that the result can
+// never point outside of rootfs, no matter what the path or the symlinks it
+// passes through say. This is the containment logic relied on by
+// TestCpAbsoluteSymlink and TestCpSymlinkComponent, and is shared by both
+// the download (container -> host) and upload (host -> container) cp paths.
+func ResolveInRootfs(rootfs, path string) (string, error) {
+	// Treat path as rootfs-relative regardless of whether it was given as
+	// absolute or relative; a garbage "../../.." prefix collapses away.
+	cleaned := filepath.Clean(string(os.PathSeparator) + path)
+
+	resolved, err := resolveSymlinks(rootfs, cleaned, 0)
+	if err != nil {
+		return "", err
+	}
+
+	return resolved, nil
+}
+
+// resolveSymlinks walks rel (a rootfs-relative, cleaned, absolute-style
+// path) component by component, resolving any symlink it encounters against
+// rootfs, and returns the final host path.
+func resolveSymlinks(rootfs, rel string, depth int) (string, error) {
+	if depth > maxSymlinkDepth {
+		return "", errors.New("archive: too many levels of symbolic links")
+	}
+
+	components := strings.Split(rel, string(os.PathSeparator))
+
+	current := rootfs
+	for i, component := range components {
+		if component == "" {
+			continue
+		}
+
+		next := filepath.Join(current, component)
+
+		info, err := os.Lstat(next)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// The remaining components don't exist yet (e.g. an
+				// upload destination); nothing left to resolve can be a
+				// symlink, so just join the rest verbatim.
+				return filepath.Join(append([]string{current}, components[i:]...)...), nil
+			}
+			return "", err
+		}
+
+		if info.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		link, err := os.Readlink(next)
+		if err != nil {
+			return "", err
+		}
+
+		var linkRel string
+		if filepath.IsAbs(link) {
+			linkRel = link
+		} else {
+			hostRel, err := filepath.Rel(rootfs, current)
+			if err != nil {
+				return "", err
+			}
+			linkRel = filepath.Join(string(os.PathSeparator)+hostRel, link)
+		}
+
+		remaining := filepath.Join(components[i+1:]...)
+		resolved, err := resolveSymlinks(rootfs, filepath.Clean(string(os.PathSeparator)+linkRel), depth+1)
+		if err != nil {
+			return "", err
+		}
+
+		if remaining == "" {
+			return resolved, nil
+		}
+		return resolveSymlinks(rootfs, filepath.Join(string(os.PathSeparator), mustRel(rootfs, resolved), remaining), depth+1)
+	}
+
+	return current, nil
+}
+
+func mustRel(base, target string) string {
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return target
+	}
+	return rel
+}
+
+// IsGlob reports whether path contains a shell-style wildcard character.
+func IsGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// ResolveGlobInRootfs resolves a glob pattern such as "/etc/*.conf" against
+// rootfs. The pattern is cleaned exactly like ResolveInRootfs cleans a
+// literal path, so a ".." can't survive to walk back out, and is then
+// matched component by component: each component, wildcarded or not, is
+// only matched once everything before it has been fully symlink-resolved
+// against rootfs via ResolveInRootfs, so a wildcard can never walk through
+// a symlinked directory and out of the rootfs either. It returns the host
+// paths of every match.
+func ResolveGlobInRootfs(rootfs, pattern string) ([]string, error) {
+	cleaned := filepath.Clean(string(os.PathSeparator) + pattern)
+
+	var components []string
+	for _, c := range strings.Split(cleaned, string(os.PathSeparator)) {
+		if c != "" {
+			components = append(components, c)
+		}
+	}
+
+	return globComponents(rootfs, "", components, 0)
+}
+
+// globComponents matches the remaining path components against entries
+// under rootfs/resolvedRel, descending one component at a time. Literal
+// components are joined onto resolvedRel unresolved; resolvedRel is
+// resolved through ResolveInRootfs before every wildcard match and once
+// more for each final result, so every directory read and every path
+// returned is inside rootfs, no matter what symlinks the components
+// crossed to get there.
+func globComponents(rootfs, resolvedRel string, components []string, depth int) ([]string, error) {
+	if depth > maxSymlinkDepth {
+		return nil, errors.New("archive: too many levels of symbolic links")
+	}
+
+	if len(components) == 0 {
+		resolved, err := ResolveInRootfs(rootfs, resolvedRel)
+		if err != nil {
+			return nil, err
+		}
+		return []string{resolved}, nil
+	}
+
+	component, rest := components[0], components[1:]
+
+	if !IsGlob(component) {
+		return globComponents(rootfs, filepath.Join(resolvedRel, component), rest, depth+1)
+	}
+
+	currentDir, err := ResolveInRootfs(rootfs, resolvedRel)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ioutil.ReadDir(currentDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		ok, err := filepath.Match(component, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		sub, err := globComponents(rootfs, filepath.Join(resolvedRel, entry.Name()), rest, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, sub...)
+	}
+	return matches, nil
+}
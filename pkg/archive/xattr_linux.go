@@ -0,0 +1,123 @@
+package archive
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// lgetXattrs lists and reads the extended attribute set of path (not
+// following symlinks), used to carry file capabilities and other xattrs
+// across an archive-mode `docker cp`.
+func lgetXattrs(path string) (map[string][]byte, error) {
+	names, err := llistXattr(path)
+	if err != nil {
+		return nil, err
+	}
+
+	xattrs := make(map[string][]byte, len(names))
+	for _, name := range names {
+		value, err := lgetXattr(path, name)
+		if err != nil {
+			continue
+		}
+		xattrs[name] = value
+	}
+	return xattrs, nil
+}
+
+func lsetXattr(path, name string, value []byte) error {
+	pathBytes, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	nameBytes, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+
+	var valuePtr unsafe.Pointer
+	if len(value) > 0 {
+		valuePtr = unsafe.Pointer(&value[0])
+	}
+
+	_, _, errno := syscall.Syscall6(syscall.SYS_LSETXATTR,
+		uintptr(unsafe.Pointer(pathBytes)),
+		uintptr(unsafe.Pointer(nameBytes)),
+		uintptr(valuePtr),
+		uintptr(len(value)),
+		0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func llistXattr(path string) ([]string, error) {
+	pathBytes, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	size, _, errno := syscall.Syscall(syscall.SYS_LLISTXATTR, uintptr(unsafe.Pointer(pathBytes)), 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	if size == 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, size)
+	n, _, errno := syscall.Syscall(syscall.SYS_LLISTXATTR,
+		uintptr(unsafe.Pointer(pathBytes)),
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(len(buf)))
+	if errno != 0 {
+		return nil, errno
+	}
+
+	var names []string
+	start := 0
+	for i := 0; i < int(n); i++ {
+		if buf[i] == 0 {
+			if i > start {
+				names = append(names, string(buf[start:i]))
+			}
+			start = i + 1
+		}
+	}
+	return names, nil
+}
+
+func lgetXattr(path, name string) ([]byte, error) {
+	pathBytes, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	nameBytes, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	size, _, errno := syscall.Syscall6(syscall.SYS_LGETXATTR,
+		uintptr(unsafe.Pointer(pathBytes)),
+		uintptr(unsafe.Pointer(nameBytes)),
+		0, 0, 0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	if size == 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, size)
+	n, _, errno := syscall.Syscall6(syscall.SYS_LGETXATTR,
+		uintptr(unsafe.Pointer(pathBytes)),
+		uintptr(unsafe.Pointer(nameBytes)),
+		uintptr(unsafe.Pointer(&buf[0])),
+		uintptr(len(buf)),
+		0, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	return buf[:n], nil
+}
@@ -0,0 +1,14 @@
+// +build !linux
+
+package archive
+
+// Extended attributes are a Linux-specific concept here; other platforms
+// simply carry no xattrs across an archive-mode `docker cp`.
+
+func lgetXattrs(path string) (map[string][]byte, error) {
+	return nil, nil
+}
+
+func lsetXattr(path, name string, value []byte) error {
+	return nil
+}
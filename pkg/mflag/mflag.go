@@ -0,0 +1,96 @@
+// Package mflag implements command-line flag parsing in the style docker's
+// CLI commands use: a single flag can be registered under several names at
+// once (e.g. a short "a" and a long "-archive"), with every name setting
+// the same value. It is a thin wrapper around the standard library's flag
+// package, which already parses "-x" and "--x" identically, so all mflag
+// needs to do is register each alias under its bare name.
+package mflag
+
+import (
+	"flag"
+	"strings"
+)
+
+// ErrorHandling mirrors flag.ErrorHandling so callers don't need to import
+// both packages.
+type ErrorHandling = flag.ErrorHandling
+
+// Re-exported so callers can write mflag.ExitOnError etc.
+const (
+	ContinueOnError = flag.ContinueOnError
+	ExitOnError     = flag.ExitOnError
+	PanicOnError    = flag.PanicOnError
+)
+
+// FlagSet wraps a flag.FlagSet, adding support for registering a flag under
+// multiple names.
+type FlagSet struct {
+	flag.FlagSet
+
+	// Usage, if set, is called when Parse fails or -h/-help is given. When
+	// it is nil, nothing is printed.
+	Usage func()
+}
+
+// NewFlagSet returns a new, empty FlagSet with the given name and error
+// handling behavior.
+func NewFlagSet(name string, errorHandling ErrorHandling) *FlagSet {
+	fs := &FlagSet{}
+	fs.FlagSet.Init(name, errorHandling)
+	fs.FlagSet.Usage = func() {
+		if fs.Usage != nil {
+			fs.Usage()
+		}
+	}
+	return fs
+}
+
+// aliases strips the leading "-" docker's CLI commands use to mark a name
+// as the "long" form; the underlying flag package parses "-x" and "--x" the
+// same way regardless, so the distinction only matters for display, not
+// parsing.
+func aliases(names []string) []string {
+	stripped := make([]string, len(names))
+	for i, name := range names {
+		stripped[i] = strings.TrimPrefix(name, "-")
+	}
+	return stripped
+}
+
+// Bool defines a bool flag under every name in names, all sharing the same
+// value.
+func (fs *FlagSet) Bool(names []string, value bool, usage string) *bool {
+	p := new(bool)
+	fs.BoolVar(p, names, value, usage)
+	return p
+}
+
+// BoolVar is like Bool but stores the value in p.
+func (fs *FlagSet) BoolVar(p *bool, names []string, value bool, usage string) {
+	for _, name := range aliases(names) {
+		fs.FlagSet.BoolVar(p, name, value, usage)
+	}
+}
+
+// String defines a string flag under every name in names, all sharing the
+// same value.
+func (fs *FlagSet) String(names []string, value string, usage string) *string {
+	p := new(string)
+	fs.StringVar(p, names, value, usage)
+	return p
+}
+
+// StringVar is like String but stores the value in p.
+func (fs *FlagSet) StringVar(p *string, names []string, value string, usage string) {
+	for _, name := range aliases(names) {
+		fs.FlagSet.StringVar(p, name, value, usage)
+	}
+}
+
+// Var defines a flag.Value flag under every name in names, all sharing the
+// same underlying value.
+func (fs *FlagSet) Var(value flag.Value, names []string, usage string) {
+	for _, name := range aliases(names) {
+		fs.FlagSet.Var(value, name, usage)
+	}
+}